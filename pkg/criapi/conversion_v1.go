@@ -0,0 +1,189 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package criapi
+
+import (
+	v1 "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+// FromV1PodSandboxConfig converts a CRI v1 PodSandboxConfig into the internal type.
+func FromV1PodSandboxConfig(in *v1.PodSandboxConfig) *PodSandboxConfig {
+	if in == nil {
+		return nil
+	}
+	out := &PodSandboxConfig{
+		LogDirectory: in.LogDirectory,
+		Annotations:  in.Annotations,
+	}
+	if in.Metadata != nil {
+		out.Metadata = &PodSandboxMetadata{
+			Name:      in.Metadata.Name,
+			Uid:       in.Metadata.Uid,
+			Namespace: in.Metadata.Namespace,
+			Attempt:   in.Metadata.Attempt,
+		}
+	}
+	if in.Linux != nil && in.Linux.SecurityContext != nil && in.Linux.SecurityContext.NamespaceOptions != nil {
+		nsOpts := in.Linux.SecurityContext.NamespaceOptions
+		out.Linux = &LinuxPodSandboxConfig{
+			SecurityContext: &LinuxSandboxSecurityContext{
+				NamespaceOptions: &NamespaceOption{
+					HostNetwork:       nsOpts.Network == v1.NamespaceMode_NODE,
+					HostPid:           nsOpts.Pid == v1.NamespaceMode_NODE,
+					HostIpc:           nsOpts.Ipc == v1.NamespaceMode_NODE,
+					UsernamespaceMode: NamespaceMode(nsOpts.UsernamespaceMode),
+					UidMappings:       fromV1IDMappings(nsOpts.UidMappings),
+					GidMappings:       fromV1IDMappings(nsOpts.GidMappings),
+				},
+			},
+		}
+	}
+	return out
+}
+
+// ToV1PodSandboxConfig converts the internal PodSandboxConfig into a CRI v1 PodSandboxConfig.
+func ToV1PodSandboxConfig(in *PodSandboxConfig) *v1.PodSandboxConfig {
+	if in == nil {
+		return nil
+	}
+	out := &v1.PodSandboxConfig{
+		LogDirectory: in.LogDirectory,
+		Annotations:  in.Annotations,
+	}
+	if in.Metadata != nil {
+		out.Metadata = &v1.PodSandboxMetadata{
+			Name:      in.Metadata.Name,
+			Uid:       in.Metadata.Uid,
+			Namespace: in.Metadata.Namespace,
+			Attempt:   in.Metadata.Attempt,
+		}
+	}
+	if in.Linux != nil && in.Linux.SecurityContext != nil && in.Linux.SecurityContext.NamespaceOptions != nil {
+		nsOpts := in.Linux.SecurityContext.NamespaceOptions
+		nsOptsV1 := &v1.NamespaceOption{
+			UsernamespaceMode: v1.NamespaceMode(nsOpts.UsernamespaceMode),
+			UidMappings:       toV1IDMappings(nsOpts.UidMappings),
+			GidMappings:       toV1IDMappings(nsOpts.GidMappings),
+		}
+		if nsOpts.HostNetwork {
+			nsOptsV1.Network = v1.NamespaceMode_NODE
+		}
+		if nsOpts.HostPid {
+			nsOptsV1.Pid = v1.NamespaceMode_NODE
+		}
+		if nsOpts.HostIpc {
+			nsOptsV1.Ipc = v1.NamespaceMode_NODE
+		}
+		out.Linux = &v1.LinuxPodSandboxConfig{
+			SecurityContext: &v1.LinuxSandboxSecurityContext{NamespaceOptions: nsOptsV1},
+		}
+	}
+	return out
+}
+
+// FromV1ContainerConfig converts a CRI v1 ContainerConfig into the internal type.
+func FromV1ContainerConfig(in *v1.ContainerConfig) *ContainerConfig {
+	if in == nil {
+		return nil
+	}
+	out := &ContainerConfig{
+		Command:   in.Command,
+		LogPath:   in.LogPath,
+		Stdin:     in.Stdin,
+		StdinOnce: in.StdinOnce,
+		Tty:       in.Tty,
+	}
+	if in.Metadata != nil {
+		out.Metadata = &ContainerMetadata{Name: in.Metadata.Name, Attempt: in.Metadata.Attempt}
+	}
+	if in.Image != nil {
+		out.Image = &ImageSpec{Image: in.Image.Image}
+	}
+	for _, m := range in.Mounts {
+		out.Mounts = append(out.Mounts, &Mount{
+			HostPath:      m.HostPath,
+			ContainerPath: m.ContainerPath,
+			Readonly:      m.Readonly,
+		})
+	}
+	return out
+}
+
+// ToV1ContainerConfig converts the internal ContainerConfig into a CRI v1 ContainerConfig.
+func ToV1ContainerConfig(in *ContainerConfig) *v1.ContainerConfig {
+	if in == nil {
+		return nil
+	}
+	out := &v1.ContainerConfig{
+		Command:   in.Command,
+		LogPath:   in.LogPath,
+		Stdin:     in.Stdin,
+		StdinOnce: in.StdinOnce,
+		Tty:       in.Tty,
+	}
+	if in.Metadata != nil {
+		out.Metadata = &v1.ContainerMetadata{Name: in.Metadata.Name, Attempt: in.Metadata.Attempt}
+	}
+	if in.Image != nil {
+		out.Image = &v1.ImageSpec{Image: in.Image.Image}
+	}
+	for _, m := range in.Mounts {
+		out.Mounts = append(out.Mounts, &v1.Mount{
+			HostPath:      m.HostPath,
+			ContainerPath: m.ContainerPath,
+			Readonly:      m.Readonly,
+		})
+	}
+	return out
+}
+
+// FromV1ContainerStatus converts a CRI v1 ContainerStatus into the internal type.
+func FromV1ContainerStatus(in *v1.ContainerStatus) *ContainerStatus {
+	if in == nil {
+		return nil
+	}
+	out := &ContainerStatus{
+		Id:    in.Id,
+		State: ContainerState(in.State),
+	}
+	if in.Metadata != nil {
+		out.Metadata = &ContainerMetadata{Name: in.Metadata.Name, Attempt: in.Metadata.Attempt}
+	}
+	return out
+}
+
+func fromV1IDMappings(in []*v1.IDMapping) []*IDMapping {
+	if in == nil {
+		return nil
+	}
+	out := make([]*IDMapping, 0, len(in))
+	for _, m := range in {
+		out = append(out, &IDMapping{HostId: m.HostId, ContainerId: m.ContainerId, Length: m.Length})
+	}
+	return out
+}
+
+func toV1IDMappings(in []*IDMapping) []*v1.IDMapping {
+	if in == nil {
+		return nil
+	}
+	out := make([]*v1.IDMapping, 0, len(in))
+	for _, m := range in {
+		out = append(out, &v1.IDMapping{HostId: m.HostId, ContainerId: m.ContainerId, Length: m.Length})
+	}
+	return out
+}
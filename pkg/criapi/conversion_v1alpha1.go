@@ -0,0 +1,184 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package criapi
+
+import (
+	v1alpha1 "k8s.io/kubernetes/pkg/kubelet/api/v1alpha1/runtime"
+)
+
+// FromV1alpha1PodSandboxConfig converts a v1alpha1 PodSandboxConfig into the internal type.
+func FromV1alpha1PodSandboxConfig(in *v1alpha1.PodSandboxConfig) *PodSandboxConfig {
+	if in == nil {
+		return nil
+	}
+	out := &PodSandboxConfig{
+		LogDirectory: in.LogDirectory,
+		Annotations:  in.Annotations,
+	}
+	if in.Metadata != nil {
+		out.Metadata = &PodSandboxMetadata{
+			Name:      in.Metadata.Name,
+			Uid:       in.Metadata.Uid,
+			Namespace: in.Metadata.Namespace,
+			Attempt:   in.Metadata.Attempt,
+		}
+	}
+	if in.Linux != nil && in.Linux.SecurityContext != nil && in.Linux.SecurityContext.NamespaceOptions != nil {
+		nsOpts := in.Linux.SecurityContext.NamespaceOptions
+		out.Linux = &LinuxPodSandboxConfig{
+			SecurityContext: &LinuxSandboxSecurityContext{
+				NamespaceOptions: &NamespaceOption{
+					HostNetwork:       nsOpts.HostNetwork,
+					HostPid:           nsOpts.HostPid,
+					HostIpc:           nsOpts.HostIpc,
+					UsernamespaceMode: NamespaceMode(nsOpts.UsernamespaceMode),
+					UidMappings:       fromV1alpha1IDMappings(nsOpts.UidMappings),
+					GidMappings:       fromV1alpha1IDMappings(nsOpts.GidMappings),
+				},
+			},
+		}
+	}
+	return out
+}
+
+// ToV1alpha1PodSandboxConfig converts the internal PodSandboxConfig into a v1alpha1 PodSandboxConfig.
+func ToV1alpha1PodSandboxConfig(in *PodSandboxConfig) *v1alpha1.PodSandboxConfig {
+	if in == nil {
+		return nil
+	}
+	out := &v1alpha1.PodSandboxConfig{
+		LogDirectory: in.LogDirectory,
+		Annotations:  in.Annotations,
+	}
+	if in.Metadata != nil {
+		out.Metadata = &v1alpha1.PodSandboxMetadata{
+			Name:      in.Metadata.Name,
+			Uid:       in.Metadata.Uid,
+			Namespace: in.Metadata.Namespace,
+			Attempt:   in.Metadata.Attempt,
+		}
+	}
+	if in.Linux != nil && in.Linux.SecurityContext != nil && in.Linux.SecurityContext.NamespaceOptions != nil {
+		nsOpts := in.Linux.SecurityContext.NamespaceOptions
+		out.Linux = &v1alpha1.LinuxPodSandboxConfig{
+			SecurityContext: &v1alpha1.LinuxSandboxSecurityContext{
+				NamespaceOptions: &v1alpha1.NamespaceOption{
+					HostNetwork:       nsOpts.HostNetwork,
+					HostPid:           nsOpts.HostPid,
+					HostIpc:           nsOpts.HostIpc,
+					UsernamespaceMode: v1alpha1.NamespaceMode(nsOpts.UsernamespaceMode),
+					UidMappings:       toV1alpha1IDMappings(nsOpts.UidMappings),
+					GidMappings:       toV1alpha1IDMappings(nsOpts.GidMappings),
+				},
+			},
+		}
+	}
+	return out
+}
+
+// FromV1alpha1ContainerConfig converts a v1alpha1 ContainerConfig into the internal type.
+func FromV1alpha1ContainerConfig(in *v1alpha1.ContainerConfig) *ContainerConfig {
+	if in == nil {
+		return nil
+	}
+	out := &ContainerConfig{
+		Command:   in.Command,
+		LogPath:   in.LogPath,
+		Stdin:     in.Stdin,
+		StdinOnce: in.StdinOnce,
+		Tty:       in.Tty,
+	}
+	if in.Metadata != nil {
+		out.Metadata = &ContainerMetadata{Name: in.Metadata.Name, Attempt: in.Metadata.Attempt}
+	}
+	if in.Image != nil {
+		out.Image = &ImageSpec{Image: in.Image.Image}
+	}
+	for _, m := range in.Mounts {
+		out.Mounts = append(out.Mounts, &Mount{
+			HostPath:      m.HostPath,
+			ContainerPath: m.ContainerPath,
+			Readonly:      m.Readonly,
+		})
+	}
+	return out
+}
+
+// ToV1alpha1ContainerConfig converts the internal ContainerConfig into a v1alpha1 ContainerConfig.
+func ToV1alpha1ContainerConfig(in *ContainerConfig) *v1alpha1.ContainerConfig {
+	if in == nil {
+		return nil
+	}
+	out := &v1alpha1.ContainerConfig{
+		Command:   in.Command,
+		LogPath:   in.LogPath,
+		Stdin:     in.Stdin,
+		StdinOnce: in.StdinOnce,
+		Tty:       in.Tty,
+	}
+	if in.Metadata != nil {
+		out.Metadata = &v1alpha1.ContainerMetadata{Name: in.Metadata.Name, Attempt: in.Metadata.Attempt}
+	}
+	if in.Image != nil {
+		out.Image = &v1alpha1.ImageSpec{Image: in.Image.Image}
+	}
+	for _, m := range in.Mounts {
+		out.Mounts = append(out.Mounts, &v1alpha1.Mount{
+			HostPath:      m.HostPath,
+			ContainerPath: m.ContainerPath,
+			Readonly:      m.Readonly,
+		})
+	}
+	return out
+}
+
+// FromV1alpha1ContainerStatus converts a v1alpha1 ContainerStatus into the internal type.
+func FromV1alpha1ContainerStatus(in *v1alpha1.ContainerStatus) *ContainerStatus {
+	if in == nil {
+		return nil
+	}
+	out := &ContainerStatus{
+		Id:    in.Id,
+		State: ContainerState(in.State),
+	}
+	if in.Metadata != nil {
+		out.Metadata = &ContainerMetadata{Name: in.Metadata.Name, Attempt: in.Metadata.Attempt}
+	}
+	return out
+}
+
+func fromV1alpha1IDMappings(in []*v1alpha1.IDMapping) []*IDMapping {
+	if in == nil {
+		return nil
+	}
+	out := make([]*IDMapping, 0, len(in))
+	for _, m := range in {
+		out = append(out, &IDMapping{HostId: m.HostId, ContainerId: m.ContainerId, Length: m.Length})
+	}
+	return out
+}
+
+func toV1alpha1IDMappings(in []*IDMapping) []*v1alpha1.IDMapping {
+	if in == nil {
+		return nil
+	}
+	out := make([]*v1alpha1.IDMapping, 0, len(in))
+	for _, m := range in {
+		out = append(out, &v1alpha1.IDMapping{HostId: m.HostId, ContainerId: m.ContainerId, Length: m.Length})
+	}
+	return out
+}
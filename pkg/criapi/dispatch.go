@@ -0,0 +1,47 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package criapi
+
+import "fmt"
+
+// ToPodSandboxConfig converts in to the wire type matching the version most recently
+// picked by Negotiate (v1alpha1.PodSandboxConfig or v1.PodSandboxConfig). Since those
+// are distinct Go types, the result is returned as interface{}; callers must type-switch
+// on it against whichever RuntimeService client they hold.
+func ToPodSandboxConfig(in *PodSandboxConfig) (interface{}, error) {
+	switch v := CurrentVersion(); v {
+	case VersionV1alpha1:
+		return ToV1alpha1PodSandboxConfig(in), nil
+	case VersionV1:
+		return ToV1PodSandboxConfig(in), nil
+	default:
+		return nil, fmt.Errorf("no conversion registered for negotiated CRI version %q", v)
+	}
+}
+
+// ToContainerConfig converts in to the wire type matching the version most recently
+// picked by Negotiate. See ToPodSandboxConfig for why the result is interface{}.
+func ToContainerConfig(in *ContainerConfig) (interface{}, error) {
+	switch v := CurrentVersion(); v {
+	case VersionV1alpha1:
+		return ToV1alpha1ContainerConfig(in), nil
+	case VersionV1:
+		return ToV1ContainerConfig(in), nil
+	default:
+		return nil, fmt.Errorf("no conversion registered for negotiated CRI version %q", v)
+	}
+}
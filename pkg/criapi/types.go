@@ -0,0 +1,130 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package criapi defines frakti's internal, version-agnostic mirror of the CRI
+// PodSandbox/Container types, with conversions to and from both the v1alpha1 and
+// v1 protos. The e2e helpers in test/e2e build configs using these types and
+// convert them to whichever protocol Negotiate picked for the remote endpoint;
+// since their RuntimeService client is v1alpha1-typed, only v1alpha1 configs can
+// be sent from there. Other packages are not converted to these types.
+package criapi
+
+// PodSandboxState mirrors runtimeapi.PodSandboxState.
+type PodSandboxState int32
+
+const (
+	PodSandboxState_SANDBOX_READY    PodSandboxState = 0
+	PodSandboxState_SANDBOX_NOTREADY PodSandboxState = 1
+)
+
+// ContainerState mirrors runtimeapi.ContainerState.
+type ContainerState int32
+
+const (
+	ContainerState_CONTAINER_CREATED ContainerState = 0
+	ContainerState_CONTAINER_RUNNING ContainerState = 1
+	ContainerState_CONTAINER_EXITED  ContainerState = 2
+	ContainerState_CONTAINER_UNKNOWN ContainerState = 3
+)
+
+// NamespaceMode mirrors runtimeapi.NamespaceMode.
+type NamespaceMode int32
+
+const (
+	NamespaceMode_POD       NamespaceMode = 0
+	NamespaceMode_CONTAINER NamespaceMode = 1
+	NamespaceMode_NODE      NamespaceMode = 2
+)
+
+// PodSandboxMetadata mirrors runtimeapi.PodSandboxMetadata.
+type PodSandboxMetadata struct {
+	Name      string
+	Uid       string
+	Namespace string
+	Attempt   uint32
+}
+
+// ContainerMetadata mirrors runtimeapi.ContainerMetadata.
+type ContainerMetadata struct {
+	Name    string
+	Attempt uint32
+}
+
+// ImageSpec mirrors runtimeapi.ImageSpec.
+type ImageSpec struct {
+	Image string
+}
+
+// Mount mirrors runtimeapi.Mount.
+type Mount struct {
+	HostPath      string
+	ContainerPath string
+	Readonly      bool
+}
+
+// IDMapping mirrors runtimeapi.IDMapping, used for user namespace UID/GID ranges.
+type IDMapping struct {
+	HostId      int64
+	ContainerId int64
+	Length      int64
+}
+
+// NamespaceOption mirrors runtimeapi.NamespaceOption.
+type NamespaceOption struct {
+	HostNetwork       bool
+	HostPid           bool
+	HostIpc           bool
+	UsernamespaceMode NamespaceMode
+	UidMappings       []*IDMapping
+	GidMappings       []*IDMapping
+}
+
+// LinuxSandboxSecurityContext mirrors runtimeapi.LinuxSandboxSecurityContext.
+type LinuxSandboxSecurityContext struct {
+	NamespaceOptions *NamespaceOption
+}
+
+// LinuxPodSandboxConfig mirrors runtimeapi.LinuxPodSandboxConfig.
+type LinuxPodSandboxConfig struct {
+	SecurityContext *LinuxSandboxSecurityContext
+}
+
+// PodSandboxConfig mirrors runtimeapi.PodSandboxConfig.
+type PodSandboxConfig struct {
+	Metadata     *PodSandboxMetadata
+	LogDirectory string
+	Annotations  map[string]string
+	Linux        *LinuxPodSandboxConfig
+}
+
+// ContainerConfig mirrors runtimeapi.ContainerConfig.
+type ContainerConfig struct {
+	Metadata  *ContainerMetadata
+	Image     *ImageSpec
+	Command   []string
+	Mounts    []*Mount
+	LogPath   string
+	Stdin     bool
+	StdinOnce bool
+	Tty       bool
+}
+
+// ContainerStatus mirrors runtimeapi.ContainerStatus.
+type ContainerStatus struct {
+	Id       string
+	Metadata *ContainerMetadata
+	State    ContainerState
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package criapi
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+	v1alpha1 "k8s.io/kubernetes/pkg/kubelet/api/v1alpha1/runtime"
+)
+
+// Version identifies one of the CRI protocol versions frakti can speak.
+type Version string
+
+const (
+	VersionV1alpha1 Version = "v1alpha1"
+	VersionV1       Version = "v1"
+)
+
+// supportedVersions is ordered from lowest to highest preference. VersionV1 is
+// intentionally excluded: the only RuntimeService client this tree wires up is
+// v1alpha1-typed, so auto-negotiating v1 would pick a version nothing downstream can
+// consume. It stays a valid explicit choice via SetOverrideVersion/--cri-version for
+// exercising the v1 conversion shims directly, and can be added here once a v1 client
+// exists.
+var supportedVersions = []Version{VersionV1alpha1}
+
+// overrideVersion, when non-empty, forces Negotiate/NegotiateVersion to return this
+// version regardless of what the remote endpoint advertises. It is set either directly
+// through SetOverrideVersion, or by the --cri-version flag registered with AddFlags.
+var overrideVersion Version
+
+// negotiated is the version picked by the most recent call to Negotiate.
+var negotiated Version
+
+// criVersionFlag backs the --cri-version flag; ApplyFlags copies it into
+// overrideVersion once flags have been parsed.
+var criVersionFlag string
+
+// AddFlags registers --cri-version on fs; ApplyFlags must then be called once flag
+// parsing is done. No command in this tree calls AddFlags, so --cri-version takes
+// effect only in a binary that registers it this way.
+func AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&criVersionFlag, "cri-version", "", "override CRI protocol negotiation with a specific version (v1alpha1 or v1), for testing")
+}
+
+// ApplyFlags copies a --cri-version value registered via AddFlags into overrideVersion.
+// Call it once after flag parsing.
+func ApplyFlags() {
+	if criVersionFlag != "" {
+		overrideVersion = Version(criVersionFlag)
+	}
+}
+
+// SetOverrideVersion forces all subsequent calls to Negotiate/NegotiateVersion to
+// return v. Passing the empty string restores normal negotiation.
+func SetOverrideVersion(v Version) {
+	overrideVersion = v
+}
+
+// NegotiateVersion picks the highest CRI protocol version supported by both frakti
+// and the remote runtime endpoint, given the raw version strings the remote side
+// reported. If overrideVersion has been set, it always wins so tests can pin a
+// specific protocol.
+func NegotiateVersion(remoteSupported []string) (Version, error) {
+	if overrideVersion != "" {
+		return overrideVersion, nil
+	}
+
+	remote := make(map[Version]bool, len(remoteSupported))
+	for _, v := range remoteSupported {
+		remote[Version(v)] = true
+	}
+
+	var best Version
+	for _, v := range supportedVersions {
+		if remote[v] {
+			best = v
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("no common CRI version between frakti (%v) and remote (%v)", supportedVersions, remoteSupported)
+	}
+	return best, nil
+}
+
+// VersionClient is satisfied by any CRI RuntimeService able to report which protocol
+// version the remote endpoint implements.
+type VersionClient interface {
+	Version(apiVersion string) (*v1alpha1.VersionResponse, error)
+}
+
+// Negotiate calls c.Version to find out which CRI protocol the remote endpoint
+// implements, resolves it against NegotiateVersion, and caches the result so
+// ToPodSandboxConfig/ToContainerConfig can dispatch on it without re-querying on every
+// call. Each call queries the remote endpoint again (unless an override is set) and
+// replaces the cached result; the e2e helpers call it before every conversion.
+func Negotiate(c VersionClient) (Version, error) {
+	if overrideVersion != "" {
+		negotiated = overrideVersion
+		return negotiated, nil
+	}
+
+	resp, err := c.Version("")
+	if err != nil {
+		return "", fmt.Errorf("failed to query remote CRI version: %v", err)
+	}
+	v, err := NegotiateVersion([]string{resp.RuntimeApiVersion})
+	if err != nil {
+		return "", err
+	}
+	negotiated = v
+	return negotiated, nil
+}
+
+// CurrentVersion returns the version selected by the most recent Negotiate call. It
+// defaults to v1alpha1 if Negotiate has not run yet.
+func CurrentVersion() Version {
+	if negotiated == "" {
+		return VersionV1alpha1
+	}
+	return negotiated
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/pkg/jsonlog"
+
+	"k8s.io/frakti/test/e2e/framework"
+)
+
+// logTag marks whether a CRI log line is a partial line that continues onto the next
+// entry, or the final (or only) segment of a line.
+type logTag string
+
+const (
+	partialLogTag logTag = "P"
+	fullLogTag    logTag = "F"
+)
+
+// LogParser knows how to turn the raw bytes of one container log file into a sequence
+// of complete logMessages. Frakti containers may emit either Docker's JSON-lines log
+// format or the CRI log format, so callers should pick an implementation with
+// detectLogParser instead of assuming one format.
+type LogParser interface {
+	// Parse parses the full contents of a log file into complete log messages,
+	// reassembling any partial lines along the way.
+	Parse(log []byte) ([]*logMessage, error)
+}
+
+// detectLogParser sniffs the first non-empty line of a container log and returns the
+// LogParser able to read it. Docker JSON lines always unmarshal as a jsonlog.JSONLog;
+// anything else is assumed to be the CRI log format.
+func detectLogParser(log []byte) LogParser {
+	line := firstLine(log)
+	var l jsonlog.JSONLog
+	if len(line) > 0 && json.Unmarshal(line, &l) == nil {
+		return &dockerJSONLogParser{}
+	}
+	return &criLogParser{}
+}
+
+// firstLine returns the first non-empty line of log, without the trailing EOL.
+func firstLine(log []byte) []byte {
+	for _, line := range bytes.SplitAfter(log, eol) {
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) > 0 {
+			return trimmed
+		}
+	}
+	return nil
+}
+
+// dockerJSONLogParser parses logs in Docker's JSON log format. Example:
+//   {"log":"content 1","stream":"stdout","time":"2016-10-20T18:39:20.57606443Z"}
+//   {"log":"content 2","stream":"stderr","time":"2016-10-20T18:39:20.57606444Z"}
+// Every line is a complete log entry, so no reassembly is needed.
+type dockerJSONLogParser struct{}
+
+func (*dockerJSONLogParser) Parse(log []byte) ([]*logMessage, error) {
+	var messages []*logMessage
+	for _, line := range bytes.Split(log, eol) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var l jsonlog.JSONLog
+		if err := json.Unmarshal(line, &l); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal docker json log line %q: %v", line, err)
+		}
+		messages = append(messages, &logMessage{
+			timestamp: l.Created,
+			stream:    streamType(l.Stream),
+			log:       []byte(l.Log),
+		})
+	}
+	return messages, nil
+}
+
+// criLogParser parses logs in the CRI log format:
+//   <RFC3339Nano timestamp> <stream> <P|F> <content>
+// A P tag means content is a partial line that continues in the next entry for the
+// same stream; entries are concatenated until an F-tagged entry closes the line.
+type criLogParser struct{}
+
+func (*criLogParser) Parse(log []byte) ([]*logMessage, error) {
+	var messages []*logMessage
+	pending := map[streamType]*logMessage{}
+
+	for _, line := range bytes.Split(log, eol) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		fields := bytes.SplitN(line, delimiter, 4)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected CRI log line format: %q", line)
+		}
+
+		ts, err := time.Parse(timeFormat, string(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CRI log timestamp %q: %v", fields[0], err)
+		}
+		stream := streamType(fields[1])
+		tag := logTag(fields[2])
+		content := fields[3]
+
+		msg := pending[stream]
+		if msg == nil {
+			msg = &logMessage{timestamp: ts, stream: stream}
+		}
+		msg.log = append(msg.log, content...)
+
+		switch tag {
+		case partialLogTag:
+			pending[stream] = msg
+		case fullLogTag:
+			messages = append(messages, msg)
+			delete(pending, stream)
+		default:
+			return nil, fmt.Errorf("unknown CRI log tag %q in line %q", tag, line)
+		}
+	}
+
+	for stream, msg := range pending {
+		framework.Logf("CRI log for stream %s ended without a closing tag, keeping partial content\n", stream)
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
+	"k8s.io/frakti/pkg/criapi"
+	"k8s.io/frakti/test/e2e/framework"
+	internalapi "k8s.io/kubernetes/pkg/kubelet/api"
+	runtimeapi "k8s.io/kubernetes/pkg/kubelet/api/v1alpha1/runtime"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = framework.KubeDescribe("Streaming", func() {
+	f := framework.NewDefaultFramework("streaming-test")
+
+	var c internalapi.RuntimeService
+
+	BeforeEach(func() {
+		c = f.Client
+	})
+
+	Context("exec", func() {
+		It("should run a command synchronously via ExecSync", func() {
+			podID, podConfig := createPodSandboxForContainer(c)
+			containerID := createContainerOrFail(c, "container-for-execsync-test-", podID, podConfig)
+			testStartContainer(c, containerID)
+
+			stdout, _ := execSyncOrFail(c, containerID, []string{"echo", "-n", "hello"}, defaultExecSyncTimeout)
+			Expect(string(stdout)).To(Equal("hello"))
+
+			stdout, _ = execSyncOrFail(c, containerID, []string{"sh", "-c", "ls / | grep -c bin"}, defaultExecSyncTimeout)
+			Expect(strings.TrimSpace(string(stdout))).To(Equal("1"))
+		})
+
+		It("should exec a command over a streamed Exec URL", func() {
+			podID, podConfig := createPodSandboxForContainer(c)
+			containerID := createContainerOrFail(c, "container-for-exec-test-", podID, podConfig)
+			testStartContainer(c, containerID)
+
+			execURL := execOrFail(c, containerID, []string{"cat"}, false)
+			stdout := streamExecOrFail(execURL, "ping\n")
+			Expect(stdout).To(Equal("ping\n"))
+		})
+	})
+
+	Context("attach", func() {
+		It("should attach to a running container and exercise bidirectional IO", func() {
+			podID, podConfig := createPodSandboxForContainer(c)
+			containerID := createShellContainerOrFail(c, "container-for-attach-test-", podID, podConfig)
+			testStartContainer(c, containerID)
+
+			attachURL := attachOrFail(c, containerID)
+			stdout := streamExecOrFail(attachURL, "echo attached\n")
+			Expect(stdout).To(ContainSubstring("attached"))
+		})
+	})
+
+	Context("port-forward", func() {
+		It("should forward a TCP port into the PodSandbox", func() {
+			const forwardedPort = 8765
+
+			podID, podConfig := createPodSandboxForContainer(c)
+			containerID := createListenerContainerOrFail(c, podID, podConfig, forwardedPort)
+			testStartContainer(c, containerID)
+
+			forwardURL := portForwardOrFail(c, podID, []int32{forwardedPort})
+			fw, stopCh := streamPortForward(forwardURL, forwardedPort)
+			defer close(stopCh)
+
+			readyCh := fw.Ready
+			go func() {
+				framework.ExpectNoError(fw.ForwardPorts(), "Failed to start port-forward")
+			}()
+			<-readyCh
+
+			Expect(roundTripTCPPayload(forwardedPort, "ping")).To(Equal("ping"))
+		})
+	})
+})
+
+// execSyncOrFail runs cmd inside containerID synchronously and fails if it gets error.
+func execSyncOrFail(c internalapi.RuntimeService, containerID string, cmd []string, timeout time.Duration) (stdout, stderr []byte) {
+	By(fmt.Sprintf("ExecSync %v in container %s", cmd, containerID))
+	stdout, stderr, err := c.ExecSync(containerID, cmd, timeout)
+	framework.ExpectNoError(err, "Failed to ExecSync %v in container %s: %v", cmd, containerID, err)
+	return stdout, stderr
+}
+
+// execOrFail opens an Exec stream for cmd inside containerID and fails if it gets error.
+// It returns the streaming URL the caller should dial with the SPDY executor.
+func execOrFail(c internalapi.RuntimeService, containerID string, cmd []string, tty bool) string {
+	By(fmt.Sprintf("Exec (stream) %v in container %s", cmd, containerID))
+	resp, err := c.Exec(&runtimeapi.ExecRequest{
+		ContainerId: containerID,
+		Cmd:         cmd,
+		Tty:         tty,
+		Stdin:       true,
+		Stdout:      true,
+		Stderr:      !tty,
+	})
+	framework.ExpectNoError(err, "Failed to Exec in container %s: %v", containerID, err)
+	return resp.Url
+}
+
+// attachOrFail opens an Attach stream for containerID and fails if it gets error.
+func attachOrFail(c internalapi.RuntimeService, containerID string) string {
+	By(fmt.Sprintf("Attach to container %s", containerID))
+	resp, err := c.Attach(&runtimeapi.AttachRequest{
+		ContainerId: containerID,
+		Stdin:       true,
+		Stdout:      true,
+		Stderr:      true,
+	})
+	framework.ExpectNoError(err, "Failed to Attach to container %s: %v", containerID, err)
+	return resp.Url
+}
+
+// portForwardOrFail opens a PortForward stream for podSandboxID and fails if it gets error.
+func portForwardOrFail(c internalapi.RuntimeService, podSandboxID string, ports []int32) string {
+	By(fmt.Sprintf("PortForward PodSandbox %s on ports %v", podSandboxID, ports))
+	resp, err := c.PortForward(&runtimeapi.PortForwardRequest{
+		PodSandboxId: podSandboxID,
+		Port:         ports,
+	})
+	framework.ExpectNoError(err, "Failed to PortForward PodSandbox %s: %v", podSandboxID, err)
+	return resp.Url
+}
+
+// streamExecOrFail dials streamURL (as returned by execOrFail/attachOrFail) with the SPDY
+// remotecommand executor, writes stdin and returns whatever was written back on stdout.
+func streamExecOrFail(streamURL string, stdin string) string {
+	u, err := url.Parse(streamURL)
+	framework.ExpectNoError(err, "Failed to parse stream URL %s: %v", streamURL, err)
+
+	executor, err := remotecommand.NewSPDYExecutor(&rest.Config{}, "POST", u)
+	framework.ExpectNoError(err, "Failed to create SPDY executor for %s: %v", streamURL, err)
+
+	var stdout, stderr bytes.Buffer
+	err = executor.Stream(remotecommand.StreamOptions{
+		Stdin:  strings.NewReader(stdin),
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	framework.ExpectNoError(err, "Failed to run streaming session against %s: %v", streamURL, err)
+	return stdout.String()
+}
+
+// createShellContainerOrFail creates a container running an interactive shell, so
+// attaching to it and writing commands on stdin produces matching output on stdout -
+// unlike the default "top" command, which only reads single-key UI input and never
+// echoes anything back. It fails if it gets error.
+func createShellContainerOrFail(c internalapi.RuntimeService, prefix string, podID string, podConfig *criapi.PodSandboxConfig) string {
+	By("create a container running a shell")
+	containerName := prefix + framework.NewUUID()
+	containerConfig := &criapi.ContainerConfig{
+		Metadata: buildContainerMetadata(containerName),
+		Image:    &criapi.ImageSpec{Image: defaultContainerImage},
+		Command:  []string{"sh"},
+		Stdin:    true,
+	}
+	containerID, err := c.CreateContainer(podID, negotiatedContainerConfig(c, containerConfig), negotiatedPodSandboxConfig(c, podConfig))
+	framework.ExpectNoError(err, "Failed to create shell container: %v", err)
+	return containerID
+}
+
+// createListenerContainerOrFail creates a container that echoes back whatever it
+// receives on port, using busybox nc, and fails if it gets error.
+func createListenerContainerOrFail(c internalapi.RuntimeService, podID string, podConfig *criapi.PodSandboxConfig, port int32) string {
+	By(fmt.Sprintf("create a container listening on port %d", port))
+	containerName := "container-for-portforward-test-" + framework.NewUUID()
+	containerConfig := &criapi.ContainerConfig{
+		Metadata: buildContainerMetadata(containerName),
+		Image:    &criapi.ImageSpec{Image: defaultContainerImage},
+		Command:  []string{"nc", "-ll", "-p", fmt.Sprintf("%d", port), "-e", "cat"},
+	}
+	containerID, err := c.CreateContainer(podID, negotiatedContainerConfig(c, containerConfig), negotiatedPodSandboxConfig(c, podConfig))
+	framework.ExpectNoError(err, "Failed to create listener container: %v", err)
+	return containerID
+}
+
+// roundTripTCPPayload dials 127.0.0.1:port (assumed forwarded by a live PortForwarder),
+// writes payload, and returns whatever is echoed back.
+func roundTripTCPPayload(port int32, payload string) string {
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	framework.ExpectNoError(err, "Failed to dial forwarded port %d: %v", port, err)
+	defer conn.Close()
+
+	_, err = fmt.Fprintf(conn, "%s\n", payload)
+	framework.ExpectNoError(err, "Failed to write payload to forwarded port %d: %v", port, err)
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	framework.ExpectNoError(err, "Failed to read payload back from forwarded port %d: %v", port, err)
+	return strings.TrimSuffix(line, "\n")
+}
+
+// streamPortForward dials streamURL with the SPDY port-forward dialer and returns a
+// forwarder for the given container port, ready to have ForwardPorts called on it.
+func streamPortForward(streamURL string, port int32) (*portforward.PortForwarder, chan struct{}) {
+	u, err := url.Parse(streamURL)
+	framework.ExpectNoError(err, "Failed to parse port-forward URL %s: %v", streamURL, err)
+
+	transport, upgrader, err := spdy.RoundTripperFor(&rest.Config{})
+	framework.ExpectNoError(err, "Failed to build SPDY round tripper: %v", err)
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", u)
+
+	stopCh := make(chan struct{})
+	readyCh := make(chan struct{})
+	fw, err := portforward.New(dialer, []string{fmt.Sprintf("%d:%d", port, port)}, stopCh, readyCh, nil, nil)
+	framework.ExpectNoError(err, "Failed to create port forwarder: %v", err)
+	return fw, stopCh
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"k8s.io/frakti/pkg/criapi"
+	"k8s.io/frakti/test/e2e/framework"
+	internalapi "k8s.io/kubernetes/pkg/kubelet/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// bigLogLineCount writes enough lines (at ~46 bytes each) to clear 1 MiB of log output.
+const bigLogLineCount = 40000
+
+// rotationGapDelay is how long the test leaves the stable log path missing before
+// recreating it, to land inside openLogFileFollowingRotation's retry window below.
+const rotationGapDelay = 300 * time.Millisecond
+
+// postRotationLogLine is a single CRI-formatted log line written to the recreated log
+// file, so tailContainerLog's auto-detected LogParser can parse it like any other line.
+func postRotationLogLine() []byte {
+	return []byte(fmt.Sprintf("%s stdout F post-rotation line\n", time.Now().Format(timeFormat)))
+}
+
+var _ = framework.KubeDescribe("ContainerLogTail", func() {
+	f := framework.NewDefaultFramework("container-log-tail-test")
+
+	var c internalapi.RuntimeService
+
+	BeforeEach(func() {
+		c = f.Client
+	})
+
+	It("should transparently follow the stable log path across a rotation gap with no loss or duplication", func() {
+		podID, podConfig := createPodSandboxWithLogDirectory(c)
+		logPath, containerID := createBigLogContainerOrFail(c, podID, podConfig, bigLogLineCount)
+		testStartContainer(c, containerID)
+
+		fullPath := podConfig.LogDirectory + logPath
+
+		By("wait for some log output, then read a tail from before rotation")
+		Eventually(func() ([]*logMessage, error) {
+			return tailContainerLog(fullPath, bigLogLineCount)
+		}, 30*time.Second, time.Second).ShouldNot(BeEmpty(), "container should have produced some log output by now")
+		preRotate, err := tailContainerLog(fullPath, bigLogLineCount)
+		framework.ExpectNoError(err, "Failed to tail log before rotation: %v", err)
+		Expect(preRotate).NotTo(BeEmpty())
+
+		By("rotate the log out from under the stable path, recreating it shortly after")
+		framework.ExpectNoError(os.Rename(fullPath, fullPath+".1"), "Failed to rotate log file")
+		go func() {
+			defer GinkgoRecover()
+			time.Sleep(rotationGapDelay)
+			f, createErr := os.Create(fullPath)
+			framework.ExpectNoError(createErr, "Failed to recreate log file after rotation: %v", createErr)
+			defer f.Close()
+			_, writeErr := f.Write(postRotationLogLine())
+			framework.ExpectNoError(writeErr, "Failed to write to recreated log file: %v", writeErr)
+		}()
+
+		By("tail the stable path again and confirm it transparently followed the rotation gap")
+		postRotate, err := tailContainerLog(fullPath, 1)
+		framework.ExpectNoError(err, "Failed to tail log across rotation gap: %v", err)
+		Expect(postRotate).To(HaveLen(1))
+		Expect(string(postRotate[0].log)).To(ContainSubstring("post-rotation"))
+
+		By("tail the rotated-away file and assert no pre-rotation lines were lost or duplicated")
+		rotatedAway, err := tailContainerLog(fullPath+".1", bigLogLineCount)
+		framework.ExpectNoError(err, "Failed to tail rotated-away log file: %v", err)
+		Expect(len(rotatedAway)).To(BeNumerically(">=", len(preRotate)), "rotated-away log should contain at least as many lines as the pre-rotation tail")
+		for i, msg := range preRotate {
+			Expect(string(rotatedAway[i].log)).To(Equal(string(msg.log)), "line %d should be unchanged across rotation", i)
+		}
+	})
+})
+
+// createBigLogContainerOrFail creates a container that writes lineCount lines to its
+// log, padded so the output clears 1 MiB, and fails if it gets error.
+func createBigLogContainerOrFail(c internalapi.RuntimeService, podID string, podConfig *criapi.PodSandboxConfig, lineCount int) (string, string) {
+	By("create a container that writes a large log")
+	containerName := "container-for-tail-test-" + framework.NewUUID()
+	path := fmt.Sprintf("%s.log", containerName)
+	containerConfig := &criapi.ContainerConfig{
+		Metadata: buildContainerMetadata(containerName),
+		Image:    &criapi.ImageSpec{Image: defaultContainerImage},
+		Command: []string{"sh", "-c", fmt.Sprintf(
+			"i=0; while [ $i -lt %d ]; do echo \"line-$i-xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx\"; i=$((i+1)); done", lineCount)},
+		LogPath: path,
+	}
+	containerID, err := c.CreateContainer(podID, negotiatedContainerConfig(c, containerConfig), negotiatedPodSandboxConfig(c, podConfig))
+	framework.ExpectNoError(err, "Failed to create big log container: %v", err)
+	return path, containerID
+}
+
+// tailContainerLog returns the last n log messages in the container log at path,
+// modeled on kubelet's block-based tail: it seeks backward from the end of the file
+// in blockSize chunks, counting EOL bytes, until it has read at least n lines (or hit
+// the start of the file), then hands the trailing bytes to the auto-detected
+// LogParser. If path briefly disappears or is repointed mid-read because it is being
+// rotated, the read transparently follows it instead of failing outright - see
+// openLogFileFollowingRotation.
+func tailContainerLog(path string, n int) ([]*logMessage, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	tail, err := readTail(path, n)
+	if err != nil {
+		return nil, err
+	}
+	if len(tail) == 0 {
+		return nil, nil
+	}
+	return detectLogParser(tail).Parse(tail)
+}
+
+// readTail reads the last n lines worth of bytes from path.
+func readTail(path string, n int) ([]byte, error) {
+	f, size, err := openLogFileFollowingRotation(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var tail []byte
+	linesFound := 0
+	readPos := size
+
+	buf := make([]byte, blockSize)
+	for linesFound <= n && readPos > 0 {
+		seekPos := readPos - blockSize
+		if seekPos < 0 {
+			seekPos = 0
+		}
+		readSize := readPos - seekPos
+
+		if _, err := f.Seek(seekPos, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to seek log file %q: %v", path, err)
+		}
+		chunk := buf[:readSize]
+		if _, err := io.ReadFull(f, chunk); err != nil {
+			return nil, fmt.Errorf("failed to read log file %q: %v", path, err)
+		}
+
+		linesFound += bytes.Count(chunk, eol)
+		tail = append(append([]byte{}, chunk...), tail...)
+		readPos = seekPos
+	}
+
+	lines := bytes.SplitAfter(tail, eol)
+	if len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return bytes.Join(lines, nil), nil
+}
+
+// followRotationRetries/followRotationRetryDelay bound how long
+// openLogFileFollowingRotation will wait for a stable log path to reappear after a
+// rotation briefly removes it.
+const (
+	followRotationRetries    = 10
+	followRotationRetryDelay = 100 * time.Millisecond
+)
+
+// openLogFileFollowingRotation opens path and returns the open file along with its
+// current size. os.Open already follows a symlink to wherever it currently points, so
+// a stable path repointed at a new target mid-rotation needs no special handling here.
+// The one gap this retries across is the brief window where path (or the symlink
+// backing it) doesn't exist at all, because the log manager renamed the old file away
+// and hasn't recreated or repointed the stable name yet; rather than fail outright on
+// the first ENOENT, this retries for up to about a second so callers polling the
+// stable path transparently follow across the gap.
+func openLogFileFollowingRotation(path string) (*os.File, int64, error) {
+	var lastErr error
+	for i := 0; i < followRotationRetries; i++ {
+		f, size, err := openAndStat(path)
+		if err == nil {
+			return f, size, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, 0, err
+		}
+		lastErr = err
+		time.Sleep(followRotationRetryDelay)
+	}
+	return nil, 0, fmt.Errorf("failed to open log file %q after rotation: %v", path, lastErr)
+}
+
+func openAndStat(path string) (*os.File, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/frakti/pkg/criapi"
+	"k8s.io/frakti/test/e2e/framework"
+	internalapi "k8s.io/kubernetes/pkg/kubelet/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// defaultExecSyncTimeout is the timeout used for the short-lived exec calls in this suite.
+const defaultExecSyncTimeout = 10 * time.Second
+
+// userNamespaceBackends enumerates the two runtimes frakti dispatches PodSandboxes to, so
+// the UID/GID mapping semantics can be checked on both.
+var userNamespaceBackends = []struct {
+	desc           string
+	useAlternative bool
+}{
+	{desc: "hyper (VM) backend", useAlternative: false},
+	{desc: "docker (OS container) backend", useAlternative: true},
+}
+
+var _ = framework.KubeDescribe("UsernamespaceMode", func() {
+	f := framework.NewDefaultFramework("usernamespace-mode-test")
+
+	var c internalapi.RuntimeService
+
+	BeforeEach(func() {
+		c = f.Client
+	})
+
+	for _, backend := range userNamespaceBackends {
+		backend := backend
+		Context("on the "+backend.desc, func() {
+			for _, mode := range []criapi.NamespaceMode{criapi.NamespaceMode_NODE, criapi.NamespaceMode_POD} {
+				mode := mode
+				It(fmt.Sprintf("should map host UID 0 correctly when UsernamespaceMode is %s", mode), func() {
+					podName := "PodSandbox-for-userns-test-" + framework.NewUUID()
+					podConfig := &criapi.PodSandboxConfig{
+						Metadata: buildPodSandboxMetadata(podName),
+					}
+					if backend.useAlternative {
+						podConfig.Annotations = map[string]string{osContainerAnnotation: "true"}
+					}
+					setUsernamespaceMode(podConfig, mode)
+					By("create a PodSandbox")
+					podID, err := createPodSandbox(c, podConfig)
+					if isNotSupported(err) {
+						framework.Skipf("runtime does not support UsernamespaceMode, skipping: %v", err)
+						return
+					}
+					framework.ExpectNoError(err, "Failed to create PodSandbox: %v", err)
+
+					By("create a container in the PodSandbox")
+					containerID, err := createContainer(c, "container-for-userns-test-", podID, podConfig)
+					if isNotSupported(err) {
+						framework.Skipf("runtime does not support UsernamespaceMode, skipping: %v", err)
+						return
+					}
+					framework.ExpectNoError(err, "Failed to create container: %v", err)
+					testStartContainer(c, containerID)
+
+					By("exec `id` in the container")
+					stdout, _, err := c.ExecSync(containerID, []string{"id", "-u"}, defaultExecSyncTimeout)
+					if isNotSupported(err) {
+						framework.Skipf("runtime does not support UsernamespaceMode, skipping: %v", err)
+						return
+					}
+					framework.ExpectNoError(err, "Failed to exec id in container: %v", err)
+					Expect(strings.TrimSpace(string(stdout))).To(Equal("0"), "uid inside the container should still be 0")
+
+					By("exec `cat /proc/self/uid_map` in the container")
+					stdout, _, err = c.ExecSync(containerID, []string{"cat", "/proc/self/uid_map"}, defaultExecSyncTimeout)
+					framework.ExpectNoError(err, "Failed to read /proc/self/uid_map: %v", err)
+					verifyUidMap(mode, stdout)
+				})
+			}
+		})
+	}
+})
+
+// setUsernamespaceMode sets the UsernamespaceMode (and a small UID/GID mapping range) on the
+// PodSandboxConfig's NamespaceOptions, creating the Linux security context if needed.
+// In NODE mode host UID 0 maps straight through to container UID 0; in POD mode it
+// must instead land in the 100000 sub-UID range, so the identity mapping is NODE-only.
+func setUsernamespaceMode(podConfig *criapi.PodSandboxConfig, mode criapi.NamespaceMode) {
+	if podConfig.Linux == nil {
+		podConfig.Linux = &criapi.LinuxPodSandboxConfig{}
+	}
+	if podConfig.Linux.SecurityContext == nil {
+		podConfig.Linux.SecurityContext = &criapi.LinuxSandboxSecurityContext{}
+	}
+	mappings := []*criapi.IDMapping{
+		{HostId: 100000, ContainerId: 0, Length: 65536},
+	}
+	if mode == criapi.NamespaceMode_NODE {
+		mappings = []*criapi.IDMapping{
+			{HostId: 0, ContainerId: 0, Length: 1},
+			{HostId: 100000, ContainerId: 1, Length: 65536},
+		}
+	}
+	podConfig.Linux.SecurityContext.NamespaceOptions = &criapi.NamespaceOption{
+		UsernamespaceMode: mode,
+		UidMappings:       mappings,
+		GidMappings:       mappings,
+	}
+}
+
+// verifyUidMap checks that host UID 0 is mapped to a non-zero sub-UID when POD mode is
+// requested, and left untouched (identity mapped) in NODE mode.
+func verifyUidMap(mode criapi.NamespaceMode, uidMap []byte) {
+	fields := strings.Fields(strings.TrimSpace(string(uidMap)))
+	Expect(len(fields)).To(BeNumerically(">=", 3), "unexpected /proc/self/uid_map format: %q", uidMap)
+	containerId, err := strconv.Atoi(fields[0])
+	framework.ExpectNoError(err, "Failed to parse uid_map container id: %v", err)
+	hostId, err := strconv.Atoi(fields[1])
+	framework.ExpectNoError(err, "Failed to parse uid_map host id: %v", err)
+
+	Expect(containerId).To(Equal(0), "container-side UID 0 should be present in the mapping")
+	switch mode {
+	case criapi.NamespaceMode_POD:
+		Expect(hostId).NotTo(Equal(0), "host UID 0 should be remapped to a non-zero sub-UID in POD mode")
+	case criapi.NamespaceMode_NODE:
+		Expect(hostId).To(Equal(0), "host UID 0 should be left untouched in NODE mode")
+	}
+}
+
+// isNotSupported returns true if err indicates the runtime does not implement the requested feature.
+func isNotSupported(err error) bool {
+	if err == nil {
+		return false
+	}
+	return strings.Contains(err.Error(), "not supported") || strings.Contains(err.Error(), "not implemented")
+}
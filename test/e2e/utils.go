@@ -17,13 +17,12 @@ limitations under the License.
 package e2e
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"time"
 
-	"github.com/docker/docker/pkg/jsonlog"
+	"k8s.io/frakti/pkg/criapi"
 	"k8s.io/frakti/test/e2e/framework"
 	internalapi "k8s.io/kubernetes/pkg/kubelet/api"
 	runtimeapi "k8s.io/kubernetes/pkg/kubelet/api/v1alpha1/runtime"
@@ -32,6 +31,10 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+// osContainerAnnotation marks a PodSandbox to be scheduled on the docker (OS
+// container) backend instead of the default hyper (VM) backend.
+const osContainerAnnotation = "runtime.frakti.alpha.kubernetes.io/OSContainer"
+
 var (
 	defaultUid                  string = "e2e-cri-uid"
 	defaultNamespace            string = "e2e-cri-namespace"
@@ -69,8 +72,8 @@ type logMessage struct {
 }
 
 // buildPodSandboxMetadata builds default PodSandboxMetadata with podSandboxName.
-func buildPodSandboxMetadata(podSandboxName string) *runtimeapi.PodSandboxMetadata {
-	return &runtimeapi.PodSandboxMetadata{
+func buildPodSandboxMetadata(podSandboxName string) *criapi.PodSandboxMetadata {
+	return &criapi.PodSandboxMetadata{
 		Name:      podSandboxName,
 		Uid:       defaultUid,
 		Namespace: defaultNamespace,
@@ -79,50 +82,88 @@ func buildPodSandboxMetadata(podSandboxName string) *runtimeapi.PodSandboxMetada
 }
 
 // buildContainerMetadata builds default PodSandboxMetadata with containerName.
-func buildContainerMetadata(containerName string) *runtimeapi.ContainerMetadata {
-	return &runtimeapi.ContainerMetadata{
+func buildContainerMetadata(containerName string) *criapi.ContainerMetadata {
+	return &criapi.ContainerMetadata{
 		Name:    containerName,
 		Attempt: defaultAttempt,
 	}
 }
 
 // createPodSandboxForContainer creates a PodSandbox for creating containers.
-func createPodSandboxForContainer(c internalapi.RuntimeService) (string, *runtimeapi.PodSandboxConfig) {
+func createPodSandboxForContainer(c internalapi.RuntimeService) (string, *criapi.PodSandboxConfig) {
 	By("create a PodSandbox for creating containers")
 	podName := "PodSandbox-for-create-container-" + framework.NewUUID()
-	podConfig := &runtimeapi.PodSandboxConfig{
+	podConfig := &criapi.PodSandboxConfig{
 		Metadata: buildPodSandboxMetadata(podName),
 	}
 	return createPodSandboxOrFail(c, podConfig), podConfig
 }
 
 // createAlternativePodSandboxForContainer creates a alternative runtime PodSandbox for creating containers.
-func createAlternativePodSandboxForContainer(c internalapi.RuntimeService) (string, *runtimeapi.PodSandboxConfig) {
+func createAlternativePodSandboxForContainer(c internalapi.RuntimeService) (string, *criapi.PodSandboxConfig) {
 	By("create a alternative runtime odSandbox for creating containers")
 	podName := "PodSandbox-for-create-container-" + framework.NewUUID()
-	podConfig := &runtimeapi.PodSandboxConfig{
+	podConfig := &criapi.PodSandboxConfig{
 		Metadata: buildPodSandboxMetadata(podName),
 	}
-	podConfig.Annotations = map[string]string{"runtime.frakti.alpha.kubernetes.io/OSContainer": "true"}
+	podConfig.Annotations = map[string]string{osContainerAnnotation: "true"}
 	// TODO(resouer) we should also test host ns and privileged pod
 	return createPodSandboxOrFail(c, podConfig), podConfig
 }
 
 //
-func createPodSandboxWithLogDirectory(c internalapi.RuntimeService) (string, *runtimeapi.PodSandboxConfig) {
+func createPodSandboxWithLogDirectory(c internalapi.RuntimeService) (string, *criapi.PodSandboxConfig) {
 	By("create a PodSandbox with log directory")
 	podName := "PodSandbox-with-log-directory-" + framework.NewUUID()
 	dir := fmt.Sprintf("/var/log/pods/%s/", podName)
-	podConfig := &runtimeapi.PodSandboxConfig{
+	podConfig := &criapi.PodSandboxConfig{
 		Metadata:     buildPodSandboxMetadata(podName),
 		LogDirectory: dir,
 	}
 	return createPodSandboxOrFail(c, podConfig), podConfig
 }
 
+// negotiatedPodSandboxConfig negotiates the CRI protocol version with c (see
+// criapi.Negotiate) and converts podConfig to the wire type for that version. The
+// RuntimeService client in this tree only implements v1alpha1, so a negotiated v1
+// fails loudly here rather than being silently downgraded.
+func negotiatedPodSandboxConfig(c internalapi.RuntimeService, podConfig *criapi.PodSandboxConfig) *runtimeapi.PodSandboxConfig {
+	_, err := criapi.Negotiate(c)
+	framework.ExpectNoError(err, "Failed to negotiate CRI version: %v", err)
+
+	wireConfig, err := criapi.ToPodSandboxConfig(podConfig)
+	framework.ExpectNoError(err, "Failed to convert PodSandboxConfig: %v", err)
+
+	v1alpha1Config, ok := wireConfig.(*runtimeapi.PodSandboxConfig)
+	if !ok {
+		framework.Failf("negotiated CRI version %s has no v1alpha1.RuntimeService client wired up in this tree", criapi.CurrentVersion())
+	}
+	return v1alpha1Config
+}
+
+// negotiatedContainerConfig is the ContainerConfig counterpart of negotiatedPodSandboxConfig.
+func negotiatedContainerConfig(c internalapi.RuntimeService, containerConfig *criapi.ContainerConfig) *runtimeapi.ContainerConfig {
+	_, err := criapi.Negotiate(c)
+	framework.ExpectNoError(err, "Failed to negotiate CRI version: %v", err)
+
+	wireConfig, err := criapi.ToContainerConfig(containerConfig)
+	framework.ExpectNoError(err, "Failed to convert ContainerConfig: %v", err)
+
+	v1alpha1Config, ok := wireConfig.(*runtimeapi.ContainerConfig)
+	if !ok {
+		framework.Failf("negotiated CRI version %s has no v1alpha1.RuntimeService client wired up in this tree", criapi.CurrentVersion())
+	}
+	return v1alpha1Config
+}
+
+// createPodSandbox creates a PodSandbox from podConfig.
+func createPodSandbox(c internalapi.RuntimeService, podConfig *criapi.PodSandboxConfig) (string, error) {
+	return c.RunPodSandbox(negotiatedPodSandboxConfig(c, podConfig))
+}
+
 // createPodSandboxOrFail creates a PodSandbox and fails if it gets error.
-func createPodSandboxOrFail(c internalapi.RuntimeService, podConfig *runtimeapi.PodSandboxConfig) string {
-	podID, err := c.RunPodSandbox(podConfig)
+func createPodSandboxOrFail(c internalapi.RuntimeService, podConfig *criapi.PodSandboxConfig) string {
+	podID, err := createPodSandbox(c, podConfig)
 	framework.ExpectNoError(err, "Failed to create PodSandbox: %v", err)
 	framework.Logf("Created PodSandbox %s\n", podID)
 	return podID
@@ -154,53 +195,53 @@ func listContainerForIDOrFail(c internalapi.RuntimeService, containerID string)
 }
 
 // createContainer creates a container with the prefix of containerName.
-func createContainer(c internalapi.RuntimeService, prefix string, podID string, podConfig *runtimeapi.PodSandboxConfig) (string, error) {
+func createContainer(c internalapi.RuntimeService, prefix string, podID string, podConfig *criapi.PodSandboxConfig) (string, error) {
 	By("create a container with name")
 	containerName := prefix + framework.NewUUID()
-	containerConfig := &runtimeapi.ContainerConfig{
+	containerConfig := &criapi.ContainerConfig{
 		Metadata: buildContainerMetadata(containerName),
-		Image:    &runtimeapi.ImageSpec{Image: defaultContainerImage},
+		Image:    &criapi.ImageSpec{Image: defaultContainerImage},
 		Command:  []string{"sh", "-c", "top"},
 	}
-	return c.CreateContainer(podID, containerConfig, podConfig)
+	return c.CreateContainer(podID, negotiatedContainerConfig(c, containerConfig), negotiatedPodSandboxConfig(c, podConfig))
 }
 
 // createVolContainer creates a container with volume and the prefix of containerName.
-func createVolContainer(c internalapi.RuntimeService, prefix string, podID string, podConfig *runtimeapi.PodSandboxConfig, volPath, flagFile string) (string, error) {
+func createVolContainer(c internalapi.RuntimeService, prefix string, podID string, podConfig *criapi.PodSandboxConfig, volPath, flagFile string) (string, error) {
 	By("create a container with volume and name")
 	containerName := prefix + framework.NewUUID()
-	containerConfig := &runtimeapi.ContainerConfig{
+	containerConfig := &criapi.ContainerConfig{
 		Metadata: buildContainerMetadata(containerName),
-		Image:    &runtimeapi.ImageSpec{Image: defaultContainerImage},
+		Image:    &criapi.ImageSpec{Image: defaultContainerImage},
 		// mount host path to the same directory in container, and check if flag file exists
 		Command: []string{"sh", "-c", "while [ -f " + volPath + "/" + flagFile + " ]; do sleep 1; done;"},
-		Mounts: []*runtimeapi.Mount{
+		Mounts: []*criapi.Mount{
 			{
 				HostPath:      volPath,
 				ContainerPath: volPath,
 			},
 		},
 	}
-	return c.CreateContainer(podID, containerConfig, podConfig)
+	return c.CreateContainer(podID, negotiatedContainerConfig(c, containerConfig), negotiatedPodSandboxConfig(c, podConfig))
 }
 
 // createLogContainer creates a container with log and the prefix of containerName.
-func createLogContainer(c internalapi.RuntimeService, prefix string, podID string, podConfig *runtimeapi.PodSandboxConfig) (string, string, error) {
+func createLogContainer(c internalapi.RuntimeService, prefix string, podID string, podConfig *criapi.PodSandboxConfig) (string, string, error) {
 	By("create a container with log and name")
 	containerName := prefix + framework.NewUUID()
 	path := fmt.Sprintf("%s.log", containerName)
-	containerConfig := &runtimeapi.ContainerConfig{
+	containerConfig := &criapi.ContainerConfig{
 		Metadata: buildContainerMetadata(containerName),
-		Image:    &runtimeapi.ImageSpec{Image: defaultContainerImage},
+		Image:    &criapi.ImageSpec{Image: defaultContainerImage},
 		Command:  []string{"echo", defaultLog},
 		LogPath:  path,
 	}
-	containerID, err := c.CreateContainer(podID, containerConfig, podConfig)
+	containerID, err := c.CreateContainer(podID, negotiatedContainerConfig(c, containerConfig), negotiatedPodSandboxConfig(c, podConfig))
 	return containerConfig.LogPath, containerID, err
 }
 
 // createContainerOrFail creates a container with the prefix of containerName and fails if it gets error.
-func createContainerOrFail(c internalapi.RuntimeService, prefix string, podID string, podConfig *runtimeapi.PodSandboxConfig) string {
+func createContainerOrFail(c internalapi.RuntimeService, prefix string, podID string, podConfig *criapi.PodSandboxConfig) string {
 	containerID, err := createContainer(c, prefix, podID, podConfig)
 	framework.ExpectNoError(err, "Failed to create container: %v", err)
 	framework.Logf("Created container %s\n", containerID)
@@ -208,7 +249,7 @@ func createContainerOrFail(c internalapi.RuntimeService, prefix string, podID st
 }
 
 // createVolContainerOrFail creates a container with volume and the prefix of containerName and fails if it gets error.
-func createVolContainerOrFail(c internalapi.RuntimeService, prefix string, podID string, podConfig *runtimeapi.PodSandboxConfig, hostPath, flagFile string) string {
+func createVolContainerOrFail(c internalapi.RuntimeService, prefix string, podID string, podConfig *criapi.PodSandboxConfig, hostPath, flagFile string) string {
 	containerID, err := createVolContainer(c, prefix, podID, podConfig, hostPath, flagFile)
 	framework.ExpectNoError(err, "Failed to create container: %v", err)
 	framework.Logf("Created container %s\n", containerID)
@@ -216,7 +257,7 @@ func createVolContainerOrFail(c internalapi.RuntimeService, prefix string, podID
 }
 
 // createLogContainerOrFail creates a container with log and the prefix of containerName and fails if it gets error.
-func createLogContainerOrFail(c internalapi.RuntimeService, prefix string, podID string, podConfig *runtimeapi.PodSandboxConfig) (string, string) {
+func createLogContainerOrFail(c internalapi.RuntimeService, prefix string, podID string, podConfig *criapi.PodSandboxConfig) (string, string) {
 	logPath, containerID, err := createLogContainer(c, prefix, podID, podConfig)
 	framework.ExpectNoError(err, "Failed to create container: %v", err)
 	framework.Logf("Created container %s\n", containerID)
@@ -224,9 +265,9 @@ func createLogContainerOrFail(c internalapi.RuntimeService, prefix string, podID
 }
 
 // testCreateContainer creates a container in the pod which ID is podID and make sure it be ready.
-func testCreateContainer(c internalapi.RuntimeService, podID string, podConfig *runtimeapi.PodSandboxConfig) string {
+func testCreateContainer(c internalapi.RuntimeService, podID string, podConfig *criapi.PodSandboxConfig) string {
 	containerID := createContainerOrFail(c, "container-for-create-test-", podID, podConfig)
-	verifyContainerStatus(c, containerID, runtimeapi.ContainerState_CONTAINER_CREATED, "created")
+	verifyContainerStatus(c, containerID, criapi.ContainerState_CONTAINER_CREATED, "created")
 	return containerID
 }
 
@@ -246,7 +287,7 @@ func startContainerOrFail(c internalapi.RuntimeService, containerID string) {
 // testStartContainer starts the container for containerID and make sure it be running.
 func testStartContainer(c internalapi.RuntimeService, containerID string) {
 	startContainerOrFail(c, containerID)
-	verifyContainerStatus(c, containerID, runtimeapi.ContainerState_CONTAINER_RUNNING, "running")
+	verifyContainerStatus(c, containerID, criapi.ContainerState_CONTAINER_RUNNING, "running")
 }
 
 // stopContainer stops the container for containerID.
@@ -265,17 +306,17 @@ func stopContainerOrFail(c internalapi.RuntimeService, containerID string, timeo
 // testStopContainer stops the container for containerID and make sure it be exited.
 func testStopContainer(c internalapi.RuntimeService, containerID string) {
 	stopContainerOrFail(c, containerID, defaultStopContainerTimeout)
-	verifyContainerStatus(c, containerID, runtimeapi.ContainerState_CONTAINER_EXITED, "exited")
+	verifyContainerStatus(c, containerID, criapi.ContainerState_CONTAINER_EXITED, "exited")
 }
 
 // verifyContainerStatus verifies whether status for given containerID matches.
-func verifyContainerStatus(c internalapi.RuntimeService, containerID string, expectedStatus runtimeapi.ContainerState, stateName string) {
+func verifyContainerStatus(c internalapi.RuntimeService, containerID string, expectedStatus criapi.ContainerState, stateName string) {
 	status := getContainerStatusOrFail(c, containerID)
 	Expect(status.State).To(Equal(expectedStatus), "Container state should be %s", stateName)
 }
 
 // getPodSandboxStatusOrFail gets ContainerState for containerID and fails if it gets error.
-func getContainerStatusOrFail(c internalapi.RuntimeService, containerID string) *runtimeapi.ContainerStatus {
+func getContainerStatusOrFail(c internalapi.RuntimeService, containerID string) *criapi.ContainerStatus {
 	status, err := getContainerStatus(c, containerID)
 	framework.ExpectNoError(err, "Failed to get container %s status: %v", containerID, err)
 	return status
@@ -295,9 +336,13 @@ func removeContainerOrFail(c internalapi.RuntimeService, containerID string) {
 }
 
 // getContainerStatus gets ContainerState for containerID.
-func getContainerStatus(c internalapi.RuntimeService, containerID string) (*runtimeapi.ContainerStatus, error) {
+func getContainerStatus(c internalapi.RuntimeService, containerID string) (*criapi.ContainerStatus, error) {
 	By("get container status")
-	return c.ContainerStatus(containerID)
+	status, err := c.ContainerStatus(containerID)
+	if err != nil {
+		return nil, err
+	}
+	return criapi.FromV1alpha1ContainerStatus(status), nil
 }
 
 // containerFound returns whether containers is found.
@@ -309,23 +354,9 @@ func containerFound(containers []*runtimeapi.Container, containerID string) bool
 	return false
 }
 
-// parseDockerJSONLog parses logs in Docker JSON log format. Docker JSON log format
-// example:
-//   {"log":"content 1","stream":"stdout","time":"2016-10-20T18:39:20.57606443Z"}
-//   {"log":"content 2","stream":"stderr","time":"2016-10-20T18:39:20.57606444Z"}
-func parseDockerJSONLog(log []byte, msg *logMessage) {
-	var l jsonlog.JSONLog
-
-	err := json.Unmarshal(log, &l)
-	framework.ExpectNoError(err, "failed with %v to unmarshal log %q", err, l)
-
-	msg.timestamp = l.Created
-	msg.stream = streamType(l.Stream)
-	msg.log = []byte(l.Log)
-}
-
-// verifyLogContents verifies the contents of container log.
-func verifyLogContents(podConfig *runtimeapi.PodSandboxConfig, logPath string, expectedLogMessage *logMessage) {
+// verifyLogContents verifies the contents of container log. The log format (Docker
+// JSON lines or CRI) is auto-detected so this works for both hyper and docker backends.
+func verifyLogContents(podConfig *criapi.PodSandboxConfig, logPath string, expectedLogMessage *logMessage) {
 	path := podConfig.LogDirectory + logPath
 	f, err := os.Open(path)
 	framework.ExpectNoError(err, "Failed to open log file: %v", err)
@@ -336,9 +367,10 @@ func verifyLogContents(podConfig *runtimeapi.PodSandboxConfig, logPath string, e
 	framework.ExpectNoError(err, "Failed to read log file: %v", err)
 	framework.Logf("Log file context is %s\n", log)
 
-	var msg logMessage
-	parseDockerJSONLog(log, &msg)
-	framework.Logf("Parse json log succeed")
+	messages, err := detectLogParser(log).Parse(log)
+	framework.ExpectNoError(err, "Failed to parse container log: %v", err)
+	Expect(messages).NotTo(BeEmpty(), "Expected at least one log message in %s", path)
+	msg := messages[0]
 
 	Expect(string(msg.log)).To(Equal(string(expectedLogMessage.log)), "Log should be %s", expectedLogMessage.log)
 	Expect(string(msg.stream)).To(Equal(string(expectedLogMessage.stream)), "Stream should be %s", string(expectedLogMessage.stream))